@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/filecoin-project/index-provider/cmd/provider/internal/config"
+	"github.com/filecoin-project/index-provider/engine"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p"
+	"github.com/urfave/cli/v2"
+)
+
+var listMhFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     "ad",
+		Usage:    "advertisement cid to list the multihashes of",
+		Required: true,
+	},
+}
+
+// call it via "provider list-mh --ad=bafy..."
+
+var ListMhCmd = &cli.Command{
+	Name:   "list-mh",
+	Usage:  "list the multihashes announced under an advertisement",
+	Flags:  listMhFlags,
+	Action: listMhCommand,
+}
+
+func listMhCommand(cctx *cli.Context) error {
+	adCid, err := cid.Decode(cctx.String("ad"))
+	if err != nil {
+		return fmt.Errorf("invalid --ad value: %w", err)
+	}
+
+	identity, err := config.CreateIdentity(os.Stdout)
+	if err != nil {
+		return err
+	}
+	privKey, err := identity.DecodePrivateKey("")
+	if err != nil {
+		return err
+	}
+
+	h, err := libp2p.New(libp2p.Identity(privKey))
+	if err != nil {
+		return err
+	}
+
+	eng, err := engine.New(engine.WithHost(h))
+	if err != nil {
+		return err
+	}
+	if err = eng.Start(context.Background()); err != nil {
+		return err
+	}
+	defer eng.Shutdown()
+
+	mhCh, errCh := eng.ListMultihashes(context.Background(), adCid)
+	for mh := range mhCh {
+		fmt.Println(mh.B58String())
+	}
+	if err := <-errCh; err != nil {
+		return err
+	}
+	return nil
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/multiformats/go-multihash"
 	"github.com/urfave/cli/v2"
 	"io"
+	"net/url"
 	"os"
 	"os/signal"
 	"strings"
@@ -63,7 +64,11 @@ func pubCommand(cctx *cli.Context) error {
 
 	contents := cctx.StringSlice("contents")
 	ctxID := cctx.String("context")
-	//ingestStr := cctx.String("indexer")
+
+	announceURLs, err := parseAnnounceURLs(cctx.String("indexer"))
+	if err != nil {
+		return err
+	}
 
 	identity, err := config.CreateIdentity(os.Stdout)
 	privKey, err := identity.DecodePrivateKey("")
@@ -105,6 +110,7 @@ func pubCommand(cctx *cli.Context) error {
 		engine.WithPublisherKind(engine.DataTransferPublisher),
 		engine.WithTopic(t),
 		engine.WithTopicName(topicName),
+		engine.WithAnnounceURLs(announceURLs),
 	)
 
 
@@ -145,9 +151,15 @@ func pubCommand(cctx *cli.Context) error {
 	}
 	fmt.Printf("ad cid: %s\n",ad.String())
 
-	err = eng.PublishLatest(context.Background())
-	if err != nil{
-		panic(err)
+	if eng.PubsubAnnounceEnabled() {
+		err = eng.PublishLatest(context.Background())
+		if err != nil{
+			panic(err)
+		}
+	}
+
+	if err = eng.AnnounceHttp(context.Background(), ad, nil); err != nil {
+		fmt.Println("failed to send http announcement:", err)
 	}
 
 
@@ -210,6 +222,28 @@ func (c *contentsIter) Next() (multihash.Multihash,error)  {
 	return mh,nil
 }
 
+// parseAnnounceURLs turns the --indexer flag value into the indexer HTTP
+// announce endpoint(s) to send ads to directly. An empty string yields no
+// URLs, meaning the ad is only broadcast over pubsub.
+func parseAnnounceURLs(indexer string) ([]*url.URL, error) {
+	indexer = strings.TrimSpace(indexer)
+	if indexer == "" {
+		return nil, nil
+	}
+
+	if !strings.Contains(indexer, "://") {
+		indexer = "http://" + indexer
+	}
+
+	u, err := url.Parse(indexer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --indexer value %q: %w", indexer, err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/announce"
+
+	return []*url.URL{u}, nil
+}
+
 func toStringArray(mas []multiaddr.Multiaddr) []string {
 	strArray := make([]string, 0)
 	for _,ma := range mas {
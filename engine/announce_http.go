@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// defaultAnnounceUserAgent is sent as the User-Agent header on HTTP
+// announcements unless overridden with WithAnnounceUserAgent.
+const defaultAnnounceUserAgent = "index-provider/0.0.1"
+
+// announceHttpTimeout bounds how long a single HTTP announcement may take,
+// so that a non-responsive indexer endpoint cannot hang the caller forever.
+const announceHttpTimeout = 10 * time.Second
+
+var announceHttpClient = &http.Client{Timeout: announceHttpTimeout}
+
+// ReceiveAnnounceMessage is the JSON body POSTed to an indexer's HTTP
+// announce endpoint. It carries the same information as the pubsub
+// announcement: the advertisement CID and the addresses it can be fetched
+// from.
+type ReceiveAnnounceMessage struct {
+	Cid   cid.Cid  `json:"cid"`
+	Addrs []string `json:"addrs"`
+}
+
+// WithAnnounceUserAgent sets the User-Agent header sent with HTTP
+// announcements. If unset, defaultAnnounceUserAgent is used.
+func WithAnnounceUserAgent(ua string) Option {
+	return func(e *Engine) error {
+		e.announceUserAgent = ua
+		return nil
+	}
+}
+
+// WithAnnounceURLs sets the indexer HTTP announce endpoints that
+// AnnounceHttp sends to when called without an explicit urls argument. This
+// lets an operator configure direct HTTP announcements once, at engine
+// construction, instead of threading urls through every NotifyPut/AnnounceHttp
+// call site.
+func WithAnnounceURLs(urls []*url.URL) Option {
+	return func(e *Engine) error {
+		e.announceURLs = urls
+		return nil
+	}
+}
+
+// WithPublisherGossip controls whether advertisements are broadcast over the
+// configured pubsub topic. It defaults to true. Set it to false to run in
+// HTTP-only mode (see WithAnnounceURLs); combine both to announce over
+// pubsub and HTTP.
+func WithPublisherGossip(enabled bool) Option {
+	return func(e *Engine) error {
+		e.publisherGossipDisabled = !enabled
+		return nil
+	}
+}
+
+// PubsubAnnounceEnabled reports whether advertisements should be broadcast
+// over pubsub, as set by WithPublisherGossip.
+func (e *Engine) PubsubAnnounceEnabled() bool {
+	return !e.publisherGossipDisabled
+}
+
+// AnnounceURLs returns the indexer HTTP announce endpoints configured via
+// WithAnnounceURLs.
+func (e *Engine) AnnounceURLs() []*url.URL {
+	return e.announceURLs
+}
+
+// AnnounceHttp POSTs an announcement of adCid to each of urls, or to the
+// engine's configured WithAnnounceURLs if urls is empty. The announcement
+// includes the provider's multiaddrs with the publisher peer ID injected
+// into each one via /p2p/<peerID>, so the indexer can dial the provider
+// directly without relying on gossip propagation to learn it.
+func (e *Engine) AnnounceHttp(ctx context.Context, adCid cid.Cid, urls []*url.URL) error {
+	if len(urls) == 0 {
+		urls = e.announceURLs
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	addrs, err := e.announceAddrs()
+	if err != nil {
+		return fmt.Errorf("failed to build announce addrs: %w", err)
+	}
+
+	body, err := json.Marshal(ReceiveAnnounceMessage{
+		Cid:   adCid,
+		Addrs: addrs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal announce message: %w", err)
+	}
+
+	var failed []string
+	for _, u := range urls {
+		if err := e.postAnnounce(ctx, u, body); err != nil {
+			log.Errorw("Failed to send http announcement", "url", u.String(), "err", err)
+			failed = append(failed, u.String())
+		} else {
+			log.Infow("Sent http announcement", "url", u.String(), "cid", adCid)
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("failed to announce to %d of %d indexer(s): %v", len(failed), len(urls), failed)
+	}
+	return nil
+}
+
+func (e *Engine) postAnnounce(ctx context.Context, u *url.URL, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	ua := e.announceUserAgent
+	if ua == "" {
+		ua = defaultAnnounceUserAgent
+	}
+	req.Header.Set("User-Agent", ua)
+
+	resp, err := announceHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("indexer responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// announceAddrs returns the provider's listen addresses with the publisher
+// peer ID encapsulated into each one, e.g. /ip4/1.2.3.4/tcp/3003/p2p/<peerID>.
+func (e *Engine) announceAddrs() ([]string, error) {
+	p2pComponent, err := multiaddr.NewMultiaddr("/p2p/" + e.h.ID().String())
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(e.h.Addrs()))
+	for _, a := range e.h.Addrs() {
+		addrs = append(addrs, a.Encapsulate(p2pComponent).String())
+	}
+	return addrs, nil
+}
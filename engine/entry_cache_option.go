@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	badger "github.com/ipfs/go-ds-badger2"
+)
+
+// WithEntryCache sets the datastore used to cache entry chunks while an
+// advertisement's entries are generated and served to indexers. By default
+// the engine keeps this cache in an in-memory LRU (see lrustore.LRUStore),
+// which doubles in size as needed to hold an entire linked list and so pins
+// it all in RAM until the indexer finishes pulling every chunk. Providers
+// publishing ads with very large entry counts can use this option to supply
+// a persistent, disk-backed datastore instead, trading memory for disk I/O.
+func WithEntryCache(ds datastore.Batching) Option {
+	return func(e *Engine) error {
+		e.cache = ds
+		return nil
+	}
+}
+
+// WithEntryCacheDir is a convenience wrapper around WithEntryCache that
+// backs the entry chunk cache with a badger datastore rooted at dir,
+// creating it if it does not already exist.
+func WithEntryCacheDir(dir string) Option {
+	return func(e *Engine) error {
+		ds, err := badger.NewDatastore(dir, nil)
+		if err != nil {
+			return fmt.Errorf("failed to open entry cache datastore at %s: %w", dir, err)
+		}
+		e.cache = ds
+		return nil
+	}
+}
+
+// WithEntryCacheTTL sets how long an entry chunk is kept in a persistent
+// entry cache (see WithEntryCache / WithEntryCacheDir) before it is pruned
+// on a later cache write. It has no effect on the default in-memory LRU
+// cache. A TTL of 0, the default, disables time-based pruning; chunks then
+// persist until DeleteCacheEntries is called for them.
+func WithEntryCacheTTL(ttl time.Duration) Option {
+	return func(e *Engine) error {
+		e.entryCacheTTL = ttl
+		return nil
+	}
+}
@@ -3,7 +3,9 @@ package engine
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"time"
 
 	provider "github.com/filecoin-project/index-provider"
 	"github.com/filecoin-project/index-provider/engine/lrustore"
@@ -24,6 +26,13 @@ func (e *Engine) mkLinkSystem() ipld.LinkSystem {
 		c := lnk.(cidlink.Link).Cid
 		log.Debugf("Triggered ReadOpener from engine's linksystem with cid (%s)", c)
 
+		// Serve the current advertisement straight from cache, skipping a
+		// reload and re-decode for every entry chunk request of the sync.
+		if cached := e.getCurAdCache(c); cached != nil {
+			log.Debugw("Using cached advertisement", "cid", c)
+			return bytes.NewBuffer(cached), nil
+		}
+
 		// Get the node from main datastore. If it is in the
 		// main datastore it means it is an advertisement.
 		val, err := e.ds.Get(lctx.Ctx, datastore.NewKey(c.String()))
@@ -43,6 +52,7 @@ func (e *Engine) mkLinkSystem() ipld.LinkSystem {
 			// If this was an advertisement, then return it.
 			if isAdvertisement(n) {
 				log.Infow("Retrieved advertisement from datastore", "cid", c, "size", len(val))
+				e.setCurAdCache(c, val)
 				return bytes.NewBuffer(val), nil
 			}
 			log.Infow("Retrieved non-advertisement object from datastore", "cid", c, "size", len(val))
@@ -127,22 +137,37 @@ func (e *Engine) mkLinkSystem() ipld.LinkSystem {
 		buf := bytes.NewBuffer(nil)
 		return buf, func(lnk ipld.Link) error {
 			c := lnk.(cidlink.Link).Cid
-			return e.ds.Put(lctx.Ctx, datastore.NewKey(c.String()), buf.Bytes())
+			val := buf.Bytes()
+			if err := e.ds.Put(lctx.Ctx, datastore.NewKey(c.String()), val); err != nil {
+				return err
+			}
+			// A newly stored advertisement makes the cached current
+			// advertisement stale.
+			if n, err := decodeIPLDNode(bytes.NewBuffer(val)); err == nil && isAdvertisement(n) {
+				e.invalidateCurAdCache()
+			}
+			return nil
 		}, nil
 	}
 	return lsys
 }
 
 // generateChunks iterates multihashes, bundles them into a chunk (slice), and
-// then and stores that chunk and a link to the previous chunk.
+// then and stores that chunk and a link to the previous chunk. On error it
+// still returns the link to the last chunk committed, so progress is not lost.
 func (e *Engine) generateChunks(mhIter provider.MultihashIterator) (ipld.Link, error) {
+	return e.generateChunksFrom(mhIter, nil)
+}
+
+// generateChunksFrom is generateChunks but appends onto fromLnk instead of starting a fresh list.
+func (e *Engine) generateChunksFrom(mhIter provider.MultihashIterator, fromLnk ipld.Link) (ipld.Link, error) {
 	chunkSize := e.linkedChunkSize
 	mhs := make([]multihash.Multihash, 0, chunkSize)
 
 	ls, lsOK := e.cache.(*lrustore.LRUStore)
 	var resized bool
 
-	var chunkLnk ipld.Link
+	chunkLnk := fromLnk
 	var totalMhCount, chunkCount int
 	for {
 		next, err := mhIter.Next()
@@ -150,7 +175,7 @@ func (e *Engine) generateChunks(mhIter provider.MultihashIterator) (ipld.Link, e
 			break
 		}
 		if err != nil {
-			return nil, err
+			return chunkLnk, fmt.Errorf("committed %d chunks (%d multihashes) before lister failed: %w", chunkCount, totalMhCount-len(mhs), err)
 		}
 		mhs = append(mhs, next)
 		totalMhCount++
@@ -161,10 +186,11 @@ func (e *Engine) generateChunks(mhIter provider.MultihashIterator) (ipld.Link, e
 				ls.Resize(context.Background(), ls.Cap()*2)
 				resized = true
 			}
-			chunkLnk, _, err = schema.NewLinkedListOfMhs(e.cachelsys, mhs, chunkLnk)
+			lnk, _, err := schema.NewLinkedListOfMhs(e.cachelsys, mhs, chunkLnk)
 			if err != nil {
-				return nil, err
+				return chunkLnk, fmt.Errorf("committed %d chunks (%d multihashes) before failing to link next chunk: %w", chunkCount, totalMhCount-len(mhs), err)
 			}
+			chunkLnk = lnk
 			chunkCount++
 			// NewLinkedListOfMhs makes it own copy, so safe to reuse mhs
 			mhs = mhs[:0]
@@ -177,11 +203,11 @@ func (e *Engine) generateChunks(mhIter provider.MultihashIterator) (ipld.Link, e
 			ls.Resize(context.Background(), ls.Cap()*2)
 			resized = true
 		}
-		var err error
-		chunkLnk, _, err = schema.NewLinkedListOfMhs(e.cachelsys, mhs, chunkLnk)
+		lnk, _, err := schema.NewLinkedListOfMhs(e.cachelsys, mhs, chunkLnk)
 		if err != nil {
-			return nil, err
+			return chunkLnk, fmt.Errorf("committed %d chunks (%d multihashes) before failing to link final chunk: %w", chunkCount, totalMhCount-len(mhs), err)
 		}
+		chunkLnk = lnk
 		chunkCount++
 	}
 
@@ -196,6 +222,125 @@ func (e *Engine) generateChunks(mhIter provider.MultihashIterator) (ipld.Link, e
 	return chunkLnk, nil
 }
 
+// ResumeChunks continues an interrupted generateChunks from fromChunkLink,
+// re-invoking the MultihashLister and seeking it past what's already linked.
+// The lister's iterator must implement provider.ResumableMultihashIterator.
+func (e *Engine) ResumeChunks(ctx context.Context, contextID []byte, fromChunkLink ipld.Link) (ipld.Link, error) {
+	if e.cb == nil {
+		return nil, provider.ErrNoCallback
+	}
+
+	mhIter, err := e.cb(ctx, contextID)
+	if err != nil {
+		return nil, err
+	}
+	resumable, ok := mhIter.(provider.ResumableMultihashIterator)
+	if !ok {
+		return nil, fmt.Errorf("multihash lister for context id does not support resuming: %T", mhIter)
+	}
+
+	committed, err := e.countLinkedMhs(ctx, fromChunkLink)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine how many multihashes were already committed: %w", err)
+	}
+	if err := resumable.SeekTo(committed); err != nil {
+		return nil, fmt.Errorf("could not seek multihash iterator to offset %d: %w", committed, err)
+	}
+
+	return e.generateChunksFrom(resumable, fromChunkLink)
+}
+
+// countLinkedMhs counts the multihashes already held by the chunk linked list rooted at lnk.
+func (e *Engine) countLinkedMhs(ctx context.Context, lnk ipld.Link) (int, error) {
+	var count int
+	for lnk != nil {
+		nd, err := e.cachelsys.Load(ipld.LinkContext{Ctx: ctx}, lnk, basicnode.Prototype.Any)
+		if err != nil {
+			return 0, err
+		}
+		entries, err := nd.LookupByString("Entries")
+		if err != nil {
+			return 0, err
+		}
+		count += int(entries.Length())
+
+		next, err := nd.LookupByString("Next")
+		if err != nil {
+			return 0, err
+		}
+		if next.IsNull() {
+			break
+		}
+		lnk, err = next.AsLink()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// pruneSweepInterval bounds how often pruneExpiredCacheEntries runs: once
+// every pruneSweepInterval cache writes, rather than on every single write.
+// Sweeping is an O(n) scan over cacheWriteTimes under cacheWriteTimesMu, and
+// running it per-chunk would make generating a large ad's entries O(n^2) and
+// add lock contention on the write hot path.
+const pruneSweepInterval = 256
+
+// noteCacheWrite records when an entry chunk was written, for WithEntryCacheTTL
+// pruning, and reports whether a pruning sweep is due.
+func (e *Engine) noteCacheWrite(c cid.Cid) bool {
+	e.cacheWriteTimesMu.Lock()
+	defer e.cacheWriteTimesMu.Unlock()
+	if e.cacheWriteTimes == nil {
+		e.cacheWriteTimes = make(map[string]time.Time)
+	}
+	e.cacheWriteTimes[c.String()] = time.Now()
+	e.cacheWriteCount++
+	return e.cacheWriteCount%pruneSweepInterval == 0
+}
+
+// DeleteCacheEntries removes entry chunks from the cache, e.g. once the indexer confirms a sync.
+func (e *Engine) DeleteCacheEntries(ctx context.Context, cids []cid.Cid) error {
+	for _, c := range cids {
+		if err := e.cache.Delete(ctx, datastore.NewKey(c.String())); err != nil && err != datastore.ErrNotFound {
+			return fmt.Errorf("failed to delete cache entry %s: %w", c, err)
+		}
+		e.cacheWriteTimesMu.Lock()
+		delete(e.cacheWriteTimes, c.String())
+		e.cacheWriteTimesMu.Unlock()
+	}
+	return nil
+}
+
+// pruneExpiredCacheEntries deletes cache entries older than WithEntryCacheTTL.
+// Called opportunistically every pruneSweepInterval cache writes (see
+// noteCacheWrite); entries with no recorded write time are left alone.
+func (e *Engine) pruneExpiredCacheEntries(ctx context.Context) {
+	if e.entryCacheTTL <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-e.entryCacheTTL)
+
+	e.cacheWriteTimesMu.Lock()
+	var expired []string
+	for k, t := range e.cacheWriteTimes {
+		if t.Before(cutoff) {
+			expired = append(expired, k)
+		}
+	}
+	e.cacheWriteTimesMu.Unlock()
+
+	for _, k := range expired {
+		if err := e.cache.Delete(ctx, datastore.NewKey(k)); err != nil && err != datastore.ErrNotFound {
+			log.Errorf("Error pruning expired cache entry %s: %s", k, err)
+			continue
+		}
+		e.cacheWriteTimesMu.Lock()
+		delete(e.cacheWriteTimes, k)
+		e.cacheWriteTimesMu.Unlock()
+	}
+}
+
 // cacheLinkSystem persist IPLD objects in an in-memory datastore.
 func (e *Engine) cacheLinkSystem() ipld.LinkSystem {
 	lsys := cidlink.DefaultLinkSystem()
@@ -215,8 +360,12 @@ func (e *Engine) cacheLinkSystem() ipld.LinkSystem {
 			err := e.cache.Put(lctx.Ctx, datastore.NewKey(c.String()), buf.Bytes())
 			if err != nil {
 				log.Errorf("Could not put cache entry for key %q", c)
+				return err
+			}
+			if _, isLRU := e.cache.(*lrustore.LRUStore); !isLRU && e.entryCacheTTL > 0 && e.noteCacheWrite(c) {
+				e.pruneExpiredCacheEntries(lctx.Ctx)
 			}
-			return err
+			return nil
 		}, nil
 	}
 	return lsys
@@ -267,6 +416,32 @@ func isAdvertisement(n ipld.Node) bool {
 	return indexID != nil
 }
 
+// getCurAdCache returns the cached advertisement bytes for c, or nil on a miss.
+func (e *Engine) getCurAdCache(c cid.Cid) []byte {
+	e.curAdMu.Lock()
+	defer e.curAdMu.Unlock()
+	if e.curAdCid == cid.Undef || e.curAdCid != c {
+		return nil
+	}
+	return e.curAdBytes
+}
+
+// setCurAdCache stashes val as the current advertisement's raw bytes under cid c.
+func (e *Engine) setCurAdCache(c cid.Cid, val []byte) {
+	e.curAdMu.Lock()
+	defer e.curAdMu.Unlock()
+	e.curAdCid = c
+	e.curAdBytes = val
+}
+
+// invalidateCurAdCache clears the cached current advertisement.
+func (e *Engine) invalidateCurAdCache() {
+	e.curAdMu.Lock()
+	defer e.curAdMu.Unlock()
+	e.curAdCid = cid.Undef
+	e.curAdBytes = nil
+}
+
 // get an entry from cache.
 func (e *Engine) getCacheEntry(ctx context.Context, c cid.Cid) ([]byte, error) {
 	b, err := e.cache.Get(ctx, datastore.NewKey(c.String()))
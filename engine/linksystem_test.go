@@ -0,0 +1,298 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	provider "github.com/filecoin-project/index-provider"
+	"github.com/filecoin-project/storetheindex/api/v0/ingest/schema"
+	"github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multihash"
+)
+
+// fakeMhIterator is a provider.ResumableMultihashIterator over a fixed slice
+// of multihashes, optionally failing on every Next call once it reaches a
+// given position.
+type fakeMhIterator struct {
+	mhs    []multihash.Multihash
+	pos    int
+	failAt int // -1 disables the synthetic failure
+}
+
+func (f *fakeMhIterator) Next() (multihash.Multihash, error) {
+	if f.failAt >= 0 && f.pos == f.failAt {
+		return nil, errors.New("synthetic lister failure")
+	}
+	if f.pos >= len(f.mhs) {
+		return nil, io.EOF
+	}
+	mh := f.mhs[f.pos]
+	f.pos++
+	return mh, nil
+}
+
+func (f *fakeMhIterator) SeekTo(n int) error {
+	f.pos = n
+	return nil
+}
+
+func testMultihashes(t *testing.T, n int) []multihash.Multihash {
+	t.Helper()
+	mhs := make([]multihash.Multihash, n)
+	for i := range mhs {
+		mh, err := multihash.Sum([]byte{byte(i)}, multihash.SHA2_256, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mhs[i] = mh
+	}
+	return mhs
+}
+
+func newTestEngine(t *testing.T) *Engine {
+	t.Helper()
+	e := &Engine{
+		ds:              dssync.MutexWrap(datastore.NewMapDatastore()),
+		cache:           dssync.MutexWrap(datastore.NewMapDatastore()),
+		linkedChunkSize: 4,
+	}
+	e.cachelsys = e.cacheLinkSystem()
+	return e
+}
+
+// storeAdvertisement stores a minimal advertisement node, with its Entries
+// link set to entriesLnk, through lsys.
+func storeAdvertisement(t *testing.T, lsys ipld.LinkSystem, entriesLnk ipld.Link) cid.Cid {
+	t.Helper()
+
+	nb := basicnode.Prototype.Map.NewBuilder()
+	ma, err := nb.BeginMap(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ma.AssembleKey().AssignString("Signature"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ma.AssembleValue().AssignBytes([]byte("sig")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ma.AssembleKey().AssignString("Entries"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ma.AssembleValue().AssignLink(entriesLnk); err != nil {
+		t.Fatal(err)
+	}
+	if err := ma.Finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	lnk, err := lsys.Store(ipld.LinkContext{Ctx: context.Background()}, schema.Linkproto, nb.Build())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lnk.(cidlink.Link).Cid
+}
+
+func TestGenerateChunksPreservesProgressOnFailure(t *testing.T) {
+	e := newTestEngine(t)
+	mhs := testMultihashes(t, 10)
+
+	lnk, err := e.generateChunks(&fakeMhIterator{mhs: mhs, failAt: 7})
+	if err == nil {
+		t.Fatal("expected an error from the failing iterator")
+	}
+	if lnk == nil {
+		t.Fatal("expected the chunk committed before the failure to still be linked")
+	}
+
+	count, err := e.countLinkedMhs(context.Background(), lnk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Fatalf("expected 4 multihashes committed before the failure, got %d", count)
+	}
+}
+
+func TestResumeChunksContinuesFromLastCommittedChunk(t *testing.T) {
+	e := newTestEngine(t)
+	mhs := testMultihashes(t, 10)
+	contextID := []byte("ctx")
+
+	lnk, err := e.generateChunks(&fakeMhIterator{mhs: mhs, failAt: 7})
+	if err == nil {
+		t.Fatal("expected the first attempt to fail")
+	}
+
+	e.cb = func(ctx context.Context, key []byte) (provider.MultihashIterator, error) {
+		return &fakeMhIterator{mhs: mhs, failAt: -1}, nil
+	}
+
+	final, err := e.ResumeChunks(context.Background(), contextID, lnk)
+	if err != nil {
+		t.Fatalf("ResumeChunks failed: %s", err)
+	}
+
+	count, err := e.countLinkedMhs(context.Background(), final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(mhs) {
+		t.Fatalf("expected all %d multihashes linked after resuming, got %d", len(mhs), count)
+	}
+}
+
+func TestResumeChunksRejectsNonResumableIterator(t *testing.T) {
+	e := newTestEngine(t)
+
+	e.cb = func(ctx context.Context, key []byte) (provider.MultihashIterator, error) {
+		return &nonResumableIterator{}, nil
+	}
+
+	if _, err := e.ResumeChunks(context.Background(), []byte("ctx"), nil); err == nil {
+		t.Fatal("expected an error when the lister's iterator cannot seek")
+	}
+}
+
+// nonResumableIterator implements provider.MultihashIterator but not
+// provider.ResumableMultihashIterator.
+type nonResumableIterator struct{}
+
+func (*nonResumableIterator) Next() (multihash.Multihash, error) {
+	return nil, io.EOF
+}
+
+func TestListMultihashesReturnsEveryEntry(t *testing.T) {
+	e := newTestEngine(t)
+	mhs := testMultihashes(t, 10)
+
+	entriesLnk, err := e.generateChunks(&fakeMhIterator{mhs: mhs, failAt: -1})
+	if err != nil {
+		t.Fatalf("generateChunks failed: %s", err)
+	}
+	adCid := storeAdvertisement(t, e.vanillaLinkSystem(), entriesLnk)
+
+	mhCh, errCh := e.ListMultihashes(context.Background(), adCid)
+	var got []multihash.Multihash
+	for mh := range mhCh {
+		got = append(got, mh)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("ListMultihashes failed: %s", err)
+	}
+	if len(got) != len(mhs) {
+		t.Fatalf("expected %d multihashes, got %d", len(mhs), len(got))
+	}
+	// The walk starts at the head of the list, which is the last chunk
+	// generated; within a chunk, multihashes keep their original order.
+	var want []multihash.Multihash
+	for start := (len(mhs) - 1) / e.linkedChunkSize * e.linkedChunkSize; start >= 0; start -= e.linkedChunkSize {
+		end := start + e.linkedChunkSize
+		if end > len(mhs) {
+			end = len(mhs)
+		}
+		want = append(want, mhs[start:end]...)
+	}
+	for i, mh := range got {
+		if !bytes.Equal(mh, want[i]) {
+			t.Fatalf("multihash %d does not match: got %s, want %s", i, mh, want[i])
+		}
+	}
+}
+
+func TestCurAdCache(t *testing.T) {
+	e := newTestEngine(t)
+	lsys := e.mkLinkSystem()
+
+	entries1, err := schema.Linkproto.Prefix.Sum([]byte("entries-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad1 := storeAdvertisement(t, lsys, cidlink.Link{Cid: entries1})
+
+	// Reading the advertisement through the main linksystem populates the
+	// current-ad cache.
+	r, err := lsys.StorageReadOpener(ipld.LinkContext{Ctx: context.Background()}, cidlink.Link{Cid: ad1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.getCurAdCache(ad1); !bytes.Equal(got, want) {
+		t.Fatalf("expected a cache hit for the advertisement just read, got %q", got)
+	}
+
+	// A different cid is a miss.
+	other, err := schema.Linkproto.Prefix.Sum([]byte("unrelated"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.getCurAdCache(other); got != nil {
+		t.Fatalf("expected a cache miss for an unrelated cid, got %q", got)
+	}
+
+	// Storing a new advertisement through the same linksystem invalidates the
+	// cached one.
+	entries2, err := schema.Linkproto.Prefix.Sum([]byte("entries-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeAdvertisement(t, lsys, cidlink.Link{Cid: entries2})
+
+	if got := e.getCurAdCache(ad1); got != nil {
+		t.Fatalf("expected the cache to be invalidated after a new advertisement was stored, got %q", got)
+	}
+}
+
+func TestPruneExpiredCacheEntriesRemovesOnlyExpired(t *testing.T) {
+	e := newTestEngine(t)
+	e.entryCacheTTL = time.Minute
+
+	stale, err := schema.Linkproto.Prefix.Sum([]byte("stale"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := schema.Linkproto.Prefix.Sum([]byte("fresh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []cid.Cid{stale, fresh} {
+		if err := e.cache.Put(context.Background(), datastore.NewKey(c.String()), []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e.noteCacheWrite(fresh)
+	e.cacheWriteTimesMu.Lock()
+	e.cacheWriteTimes[stale.String()] = time.Now().Add(-2 * time.Minute)
+	e.cacheWriteTimesMu.Unlock()
+
+	e.pruneExpiredCacheEntries(context.Background())
+
+	if _, err := e.cache.Get(context.Background(), datastore.NewKey(stale.String())); err != datastore.ErrNotFound {
+		t.Fatalf("expected stale entry to be pruned, got err %v", err)
+	}
+	if _, err := e.cache.Get(context.Background(), datastore.NewKey(fresh.String())); err != nil {
+		t.Fatalf("expected fresh entry to survive pruning, got err %v", err)
+	}
+}
+
+func TestListMultihashesErrorsOnUnknownAd(t *testing.T) {
+	e := newTestEngine(t)
+
+	_, errCh := e.ListMultihashes(context.Background(), cid.Undef)
+	if err := <-errCh; err == nil {
+		t.Fatal("expected an error for an advertisement that was never stored")
+	}
+}
@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	provider "github.com/filecoin-project/index-provider"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multihash"
+)
+
+// ListMultihashes loads the advertisement with cid adCid and streams back
+// every multihash referenced by its entries, following the chunk linked
+// list. Chunks that are still in the entry cache are read from there;
+// chunks that have been evicted are regenerated by re-invoking the
+// MultihashLister registered for the advertisement's context ID.
+//
+// The returned channels are both closed once the walk finishes, whether
+// that is because it completed or because it hit an error.
+func (e *Engine) ListMultihashes(ctx context.Context, adCid cid.Cid) (<-chan multihash.Multihash, <-chan error) {
+	mhCh := make(chan multihash.Multihash)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(mhCh)
+		defer close(errCh)
+
+		adNode, err := e.vanillaLinkSystem().Load(ipld.LinkContext{Ctx: ctx}, cidlink.Link{Cid: adCid}, basicnode.Prototype.Any)
+		if err != nil {
+			errCh <- fmt.Errorf("could not load advertisement %s: %w", adCid, err)
+			return
+		}
+
+		entriesNode, err := adNode.LookupByString("Entries")
+		if err != nil {
+			errCh <- fmt.Errorf("advertisement %s has no entries link: %w", adCid, err)
+			return
+		}
+		entriesLnk, err := entriesNode.AsLink()
+		if err != nil {
+			errCh <- fmt.Errorf("could not read entries link for advertisement %s: %w", adCid, err)
+			return
+		}
+
+		if err := e.walkEntryChunks(ctx, entriesLnk, mhCh); err != nil {
+			errCh <- err
+		}
+	}()
+
+	return mhCh, errCh
+}
+
+// walkEntryChunks follows the entry-chunk linked list starting at lnk,
+// sending every multihash it contains on mhCh. If a chunk is missing from
+// the cache, the list is regenerated once by re-invoking the registered
+// MultihashLister before giving up.
+func (e *Engine) walkEntryChunks(ctx context.Context, lnk ipld.Link, mhCh chan<- multihash.Multihash) error {
+	rootCid := lnk.(cidlink.Link).Cid
+	regenerated := false
+
+	for lnk != nil {
+		c := lnk.(cidlink.Link).Cid
+
+		raw, err := e.getCacheEntry(ctx, c)
+		if err != nil {
+			return fmt.Errorf("could not read entry chunk %s from cache: %w", c, err)
+		}
+		if raw == nil {
+			if regenerated {
+				return fmt.Errorf("entry chunk %s not found even after regenerating from lister", c)
+			}
+			if err := e.regenerateEntryChunks(ctx, rootCid); err != nil {
+				return fmt.Errorf("could not regenerate entry chunks for %s: %w", c, err)
+			}
+			regenerated = true
+			raw, err = e.getCacheEntry(ctx, c)
+			if err != nil {
+				return fmt.Errorf("could not read entry chunk %s from cache after regeneration: %w", c, err)
+			}
+			if raw == nil {
+				return fmt.Errorf("entry chunk %s not found even after regenerating from lister", c)
+			}
+		}
+
+		n, err := decodeIPLDNode(bytes.NewBuffer(raw))
+		if err != nil {
+			return fmt.Errorf("could not decode entry chunk %s: %w", c, err)
+		}
+
+		entries, err := n.LookupByString("Entries")
+		if err != nil {
+			return fmt.Errorf("entry chunk %s has no entries: %w", c, err)
+		}
+		it := entries.ListIterator()
+		for !it.Done() {
+			_, v, err := it.Next()
+			if err != nil {
+				return fmt.Errorf("could not iterate entries of chunk %s: %w", c, err)
+			}
+			b, err := v.AsBytes()
+			if err != nil {
+				return fmt.Errorf("could not read multihash from chunk %s: %w", c, err)
+			}
+			select {
+			case mhCh <- multihash.Multihash(b):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		next, err := n.LookupByString("Next")
+		if err != nil {
+			return fmt.Errorf("entry chunk %s has no next field: %w", c, err)
+		}
+		if next.IsNull() {
+			return nil
+		}
+		lnk, err = next.AsLink()
+		if err != nil {
+			return fmt.Errorf("could not read next link from chunk %s: %w", c, err)
+		}
+	}
+	return nil
+}
+
+// regenerateEntryChunks re-invokes the registered MultihashLister for the
+// context ID mapped to rootCid and rebuilds the entry chunk cache from
+// scratch, used by walkEntryChunks when it finds a chunk that has been
+// evicted from cache.
+func (e *Engine) regenerateEntryChunks(ctx context.Context, rootCid cid.Cid) error {
+	if e.cb == nil {
+		return provider.ErrNoCallback
+	}
+	key, err := e.getCidKeyMap(ctx, rootCid)
+	if err != nil {
+		return err
+	}
+	mhIter, err := e.cb(ctx, key)
+	if err != nil {
+		return err
+	}
+	_, err = e.generateChunks(mhIter)
+	return err
+}
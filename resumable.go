@@ -0,0 +1,13 @@
+package provider
+
+// ResumableMultihashIterator is a MultihashIterator that can be seeked to a
+// specific offset. Engine.ResumeChunks uses it to continue building an
+// advertisement's entry chunks from where a previous, interrupted attempt
+// left off, rather than relisting and re-chunking everything from scratch.
+type ResumableMultihashIterator interface {
+	MultihashIterator
+
+	// SeekTo advances or rewinds the iterator so that the next call to
+	// Next returns the n-th multihash (zero-indexed).
+	SeekTo(n int) error
+}